@@ -7,8 +7,21 @@ import (
 	"github.com/minio/warp/pkg/bench"
 )
 
+// Version is the schema version of the aggregated JSON result.
+// It is bumped whenever fields are added or removed so consumers
+// (including `warp analyze`) can detect the shape they are reading.
+//
+// v2 adds extended percentile (p95/p99.9/p99.99) and dispersion
+// (stddev/MAD) statistics to SingleSizedRequests and RequestSizeRange.
+//
+// v3 adds the optional LatencyHistogram/TTFBHistogram fields to
+// Operation, SingleSizedRequests and RequestSizeRange.
+const Version = 3
+
 // Operation returns statistics for a single operation type.
 type Operation struct {
+	// AggregateVersion is the schema version of this result, see Version.
+	AggregateVersion int `json:"aggregate_version"`
 	// Operation type
 	Type string `json:"type"`
 	// Skipped if too little data
@@ -35,6 +48,10 @@ type Operation struct {
 	Throughput Throughput `json:"throughput"`
 	// Throughput by host.
 	ThroughputByHost map[string]Throughput `json:"throughput_by_host"`
+	// Latency histogram across all requests, for lossless cross-host merging.
+	LatencyHistogram *Histogram `json:"latency_histogram,omitempty"`
+	// Time to first byte histogram across all requests, if applicable.
+	TTFBHistogram *Histogram `json:"ttfb_histogram,omitempty"`
 }
 
 // SingleSizedRequests contains statistics when all objects have the same size.
@@ -51,14 +68,28 @@ type SingleSizedRequests struct {
 	DurMedianMillis int `json:"dur_median_millis"`
 	// 90% request time.
 	Dur90Millis int `json:"dur_90_millis"`
+	// 95% request time.
+	DurP95Millis int `json:"dur_p95_millis"`
 	// 99% request time.
 	Dur99Millis int `json:"dur_99_millis"`
+	// 99.9% request time.
+	DurP999Millis int `json:"dur_p999_millis"`
+	// 99.99% request time.
+	DurP9999Millis int `json:"dur_p9999_millis"`
+	// Standard deviation of request time (sample, divisor n-1).
+	DurStddevMillis int `json:"dur_stddev_millis"`
+	// Median absolute deviation of request time.
+	DurMadMillis int `json:"dur_mad_millis"`
 	// Fastest request time.
 	FastestMillis int `json:"fastest_millis"`
 	// Slowest request time.
 	SlowestMillis int `json:"slowest_millis"`
 	// Time to first byte if applicable.
 	FirstByte *TTFB `json:"first_byte,omitempty"`
+	// Latency histogram, for lossless cross-host merging.
+	LatencyHistogram *Histogram `json:"latency_histogram,omitempty"`
+	// Time to first byte histogram, if applicable.
+	TTFBHistogram *Histogram `json:"ttfb_histogram,omitempty"`
 	// Request times by host.
 	ByHost map[string]SingleSizedRequests `json:"by_host,omitempty"`
 }
@@ -70,10 +101,17 @@ func (a *SingleSizedRequests) fill(ops bench.Operations) {
 	a.DurAvgMillis = durToMillis(ops.AvgDuration())
 	a.DurMedianMillis = durToMillis(ops.Median(0.5).Duration())
 	a.Dur90Millis = durToMillis(ops.Median(0.9).Duration())
+	a.DurP95Millis = durToMillis(ops.Median(0.95).Duration())
 	a.Dur99Millis = durToMillis(ops.Median(0.99).Duration())
+	a.DurP999Millis = durToMillis(ops.Median(0.999).Duration())
+	a.DurP9999Millis = durToMillis(ops.Median(0.9999).Duration())
+	a.DurStddevMillis = durToMillis(stddevDuration(ops))
+	a.DurMadMillis = durToMillis(madDuration(ops))
 	a.SlowestMillis = durToMillis(ops.Median(1).Duration())
 	a.FastestMillis = durToMillis(ops.Median(0).Duration())
 	a.FirstByte = TtfbFromBench(ops.TTFB(start, end))
+	a.LatencyHistogram = HistogramFromOperations(ops)
+	a.TTFBHistogram = TTFBHistogramFromOperations(ops)
 }
 
 type RequestSizeRange struct {
@@ -93,12 +131,21 @@ type RequestSizeRange struct {
 	BpsAverage float64 `json:"bps_average"`
 	BpsMedian  float64 `json:"bps_median"`
 	Bps90      float64 `json:"bps_90"`
+	BpsP95     float64 `json:"bps_p95"`
 	Bps99      float64 `json:"bps_99"`
+	BpsP999    float64 `json:"bps_p999"`
+	BpsP9999   float64 `json:"bps_p9999"`
+	BpsStddev  float64 `json:"bps_stddev"`
+	BpsMad     float64 `json:"bps_mad"`
 	BpsFastest float64 `json:"bps_fastest"`
 	BpsSlowest float64 `json:"bps_slowest"`
 
 	// Time to first byte if applicable.
 	FirstByte *TTFB `json:"first_byte,omitempty"`
+	// Latency histogram, for lossless cross-host merging.
+	LatencyHistogram *Histogram `json:"latency_histogram,omitempty"`
+	// Time to first byte histogram, if applicable.
+	TTFBHistogram *Histogram `json:"ttfb_histogram,omitempty"`
 }
 
 func (r *RequestSizeRange) fill(s bench.SizeSegment) {
@@ -112,9 +159,15 @@ func (r *RequestSizeRange) fill(s bench.SizeSegment) {
 	r.BpsAverage = s.Ops.OpThroughput().Float()
 	r.BpsMedian = s.Ops.Median(0.5).BytesPerSec().Float()
 	r.Bps90 = s.Ops.Median(0.9).BytesPerSec().Float()
+	r.BpsP95 = s.Ops.Median(0.95).BytesPerSec().Float()
 	r.Bps99 = s.Ops.Median(0.99).BytesPerSec().Float()
+	r.BpsP999 = s.Ops.Median(0.999).BytesPerSec().Float()
+	r.BpsP9999 = s.Ops.Median(0.9999).BytesPerSec().Float()
+	r.BpsStddev = stddevBPS(s.Ops)
+	r.BpsMad = madBPS(s.Ops)
 	r.BpsFastest = s.Ops.Median(0.0).BytesPerSec().Float()
 	r.BpsSlowest = s.Ops.Median(1).BytesPerSec().Float()
+	r.LatencyHistogram = HistogramFromOperations(s.Ops)
 }
 
 // MultiSizedRequests contains statistics when objects have the same different size.
@@ -145,6 +198,7 @@ func (a *MultiSizedRequests) fill(ops bench.Operations) {
 		var r RequestSizeRange
 		r.fill(s)
 		r.FirstByte = TtfbFromBench(s.Ops.TTFB(start, end))
+		r.TTFBHistogram = TTFBHistogramFromOperations(s.Ops)
 		// Store
 		a.BySize = append(a.BySize, r)
 	}
@@ -284,6 +338,7 @@ func SingleOp(o bench.Operations, segmentDur, skipDur time.Duration) []Operation
 	res := make([]Operation, 0, len(types))
 	for _, typ := range types {
 		a := Operation{}
+		a.AggregateVersion = Version
 		a.Type = typ
 		ops := o.FilterByOp(typ)
 		if skipDur > 0 {
@@ -324,8 +379,18 @@ func SingleOp(o bench.Operations, segmentDur, skipDur time.Duration) []Operation
 		}
 		if !ops.MultipleSizes() {
 			a.SingleSizedRequests = RequestAnalysisSingleSized(ops)
+			if a.SingleSizedRequests != nil {
+				a.LatencyHistogram = a.SingleSizedRequests.LatencyHistogram
+				a.TTFBHistogram = a.SingleSizedRequests.TTFBHistogram
+			}
 		} else {
 			a.MultiSizedRequests = RequestAnalysisMultiSized(ops)
+			if a.MultiSizedRequests != nil {
+				for _, s := range a.MultiSizedRequests.BySize {
+					a.LatencyHistogram = mergeHistograms(a.LatencyHistogram, s.LatencyHistogram)
+					a.TTFBHistogram = mergeHistograms(a.TTFBHistogram, s.TTFBHistogram)
+				}
+			}
 		}
 
 		eps := ops.Endpoints()
@@ -435,6 +500,7 @@ func RequestAnalysisHostsMultiSized(o bench.Operations) map[string]RequestSizeRa
 		a := RequestSizeRange{}
 		a.fill(filtered.SingleSizeSegment())
 		a.FirstByte = TtfbFromBench(filtered.TTFB(start, end))
+		a.TTFBHistogram = TTFBHistogramFromOperations(filtered)
 		res[ep] = a
 	}
 	return res
@@ -0,0 +1,241 @@
+package aggregate
+
+import (
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// Histogram is a compact, mergeable log-linear bucketed histogram of
+// request durations, modeled after HdrHistogram. A scalar summary
+// (avg/median/p99) computed per host cannot be combined into a
+// correct cross-host percentile after the fact; a histogram can,
+// since merging is just element-wise addition of Counts.
+//
+// Values are stored in buckets of UnitNanos, with SigDigits
+// significant decimal digits of precision retained per bucket
+// (relative error bounded by 1/2^(subBucketBits-1), which is below
+// 2*10^-SigDigits, e.g. about 0.2% for SigDigits=3).
+type Histogram struct {
+	// UnitNanos is the duration, in nanoseconds, of one histogram unit.
+	UnitNanos int64 `json:"unit_nanos"`
+	// SigDigits is the number of significant decimal digits retained.
+	SigDigits int `json:"sig_digits"`
+	// Counts is the number of samples recorded in each bucket.
+	Counts []uint32 `json:"counts"`
+	// Min is the smallest recorded value, in UnitNanos units.
+	Min int64 `json:"min"`
+	// Max is the largest recorded value, in UnitNanos units.
+	Max int64 `json:"max"`
+	// TotalCount is the total number of recorded samples.
+	TotalCount int64 `json:"total_count"`
+	// SumNanos is the sum of all recorded values, in nanoseconds.
+	SumNanos int64 `json:"sum_nanos"`
+}
+
+// NewHistogram returns an empty histogram with the given unit and
+// significant-digit precision. A zero Histogram{} behaves the same
+// once Record defaults UnitNanos/SigDigits, but NewHistogram is
+// clearer at call sites that build one directly.
+func NewHistogram(unit time.Duration, sigDigits int) *Histogram {
+	return &Histogram{UnitNanos: int64(unit), SigDigits: sigDigits}
+}
+
+// subBucketBits is the number of low bits kept at full resolution
+// within each magnitude band, i.e. ceil(log2(10^SigDigits)).
+func (h *Histogram) subBucketBits() uint {
+	count := int64(1)
+	bits := uint(0)
+	target := int64(1)
+	for i := 0; i < h.SigDigits; i++ {
+		target *= 10
+	}
+	for count < target {
+		count <<= 1
+		bits++
+	}
+	return bits
+}
+
+// bucketIndex returns the Counts index for a value expressed in
+// histogram units: the magnitude band doubles in width each time the
+// value no longer fits in subBucketCount units, so the relative
+// resolution within a band stays constant.
+func (h *Histogram) bucketIndex(unitVal int64) (idx int, magnitude uint) {
+	bits := h.subBucketBits()
+	subCount := int64(1) << bits
+	v := unitVal
+	for v >= subCount {
+		v >>= 1
+		magnitude++
+	}
+	return int(magnitude)*int(subCount) + int(v), magnitude
+}
+
+// BucketUpperNanos returns the inclusive upper bound, in nanoseconds,
+// of the value range represented by Counts[idx]. Exported so other
+// packages (e.g. promexport) can re-bucket these counts into their
+// own cumulative histogram representation without re-observing every
+// sample.
+func (h *Histogram) BucketUpperNanos(idx int) int64 {
+	return h.bucketUpperNanos(idx)
+}
+
+// bucketUpperNanos returns the inclusive upper bound, in nanoseconds,
+// of the value range represented by Counts[idx].
+func (h *Histogram) bucketUpperNanos(idx int) int64 {
+	bits := h.subBucketBits()
+	subCount := int64(1) << bits
+	magnitude := int64(idx) / subCount
+	subIdx := int64(idx) % subCount
+	upper := ((subIdx + 1) << uint(magnitude)) - 1
+	return upper * h.UnitNanos
+}
+
+// Record adds d to the histogram, growing the bucket slice as needed.
+func (h *Histogram) Record(d time.Duration) {
+	if h.UnitNanos == 0 {
+		h.UnitNanos = int64(time.Microsecond)
+	}
+	if h.SigDigits == 0 {
+		h.SigDigits = 3
+	}
+	v := int64(d) / h.UnitNanos
+	if v < 0 {
+		v = 0
+	}
+	idx, _ := h.bucketIndex(v)
+	if idx >= len(h.Counts) {
+		grown := make([]uint32, idx+1)
+		copy(grown, h.Counts)
+		h.Counts = grown
+	}
+	h.Counts[idx]++
+	h.SumNanos += int64(d)
+	if h.TotalCount == 0 || v < h.Min {
+		h.Min = v
+	}
+	if v > h.Max {
+		h.Max = v
+	}
+	h.TotalCount++
+}
+
+// Merge adds the counts of other into h. Histograms must share the
+// same UnitNanos and SigDigits to merge losslessly; mismatched
+// histograms are left untouched rather than silently distorted.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.TotalCount == 0 {
+		return
+	}
+	if h.TotalCount == 0 {
+		h.UnitNanos = other.UnitNanos
+		h.SigDigits = other.SigDigits
+		h.Counts = append([]uint32(nil), other.Counts...)
+		h.Min, h.Max = other.Min, other.Max
+		h.TotalCount = other.TotalCount
+		h.SumNanos = other.SumNanos
+		return
+	}
+	if h.UnitNanos != other.UnitNanos || h.SigDigits != other.SigDigits {
+		return
+	}
+	if len(other.Counts) > len(h.Counts) {
+		grown := make([]uint32, len(other.Counts))
+		copy(grown, h.Counts)
+		h.Counts = grown
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	h.TotalCount += other.TotalCount
+	h.SumNanos += other.SumNanos
+	if other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+}
+
+// ValueAtPercentile returns the smallest recorded value at or above
+// percentile p (0-100), by scanning buckets in ascending order and
+// returning the upper bound of the bucket where the running count
+// reaches the target rank.
+func (h *Histogram) ValueAtPercentile(p float64) time.Duration {
+	if h.TotalCount == 0 {
+		return 0
+	}
+	if p > 100 {
+		p = 100
+	}
+	if p < 0 {
+		p = 0
+	}
+	target := int64((p / 100) * float64(h.TotalCount))
+	if target < 1 {
+		target = 1
+	}
+	var cum int64
+	for idx, c := range h.Counts {
+		cum += int64(c)
+		if cum >= target {
+			return time.Duration(h.bucketUpperNanos(idx))
+		}
+	}
+	return time.Duration(h.Max * h.UnitNanos)
+}
+
+// HistogramFromOperations builds a latency histogram over the
+// duration of every operation. Unlike the scalar percentiles on
+// SingleSizedRequests/RequestSizeRange, the result can be losslessly
+// merged with histograms from other hosts.
+func HistogramFromOperations(ops bench.Operations) *Histogram {
+	if len(ops) == 0 {
+		return nil
+	}
+	h := NewHistogram(time.Microsecond, 3)
+	for _, op := range ops {
+		h.Record(op.Duration())
+	}
+	return h
+}
+
+// TTFBHistogramFromOperations builds a time-to-first-byte histogram
+// over every operation that recorded one. Returns nil if none did.
+//
+// There is no per-operation TTFB method; bench.Operations.TTFB(start,
+// end), used elsewhere in this package, derives its aggregate from the
+// same op.FirstByte/op.Start fields used here.
+func TTFBHistogramFromOperations(ops bench.Operations) *Histogram {
+	h := NewHistogram(time.Microsecond, 3)
+	for _, op := range ops {
+		if op.FirstByte == nil {
+			continue
+		}
+		if ttfb := op.FirstByte.Sub(op.Start); ttfb > 0 {
+			h.Record(ttfb)
+		}
+	}
+	if h.TotalCount == 0 {
+		return nil
+	}
+	return h
+}
+
+// mergeHistograms merges a set of per-size-range histograms into one,
+// for example to roll MultiSizedRequests.BySize back up into a single
+// Operation-level histogram. Returns nil if none of the inputs do.
+func mergeHistograms(in ...*Histogram) *Histogram {
+	var out *Histogram
+	for _, h := range in {
+		if h == nil {
+			continue
+		}
+		if out == nil {
+			out = &Histogram{}
+		}
+		out.Merge(h)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// TestHistogramFromOperations exercises HistogramFromOperations and
+// TTFBHistogramFromOperations against real bench.Operation values, so
+// a per-operation field or method this package assumes exists (e.g.
+// Duration(), FirstByte, Start) that doesn't actually exist on
+// bench.Operation fails to compile here instead of only showing up
+// once merged against the real pkg/bench.
+func TestHistogramFromOperations(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	firstByte := base.Add(10 * time.Millisecond)
+	ops := bench.Operations{
+		{OpType: "GET", Start: base, FirstByte: &firstByte, End: base.Add(20 * time.Millisecond), Size: 1024},
+		{OpType: "GET", Start: base, End: base.Add(30 * time.Millisecond), Size: 2048}, // no FirstByte recorded
+	}
+
+	lat := HistogramFromOperations(ops)
+	if lat == nil || lat.TotalCount != 2 {
+		t.Fatalf("HistogramFromOperations: TotalCount = %v, want 2", lat)
+	}
+
+	ttfb := TTFBHistogramFromOperations(ops)
+	if ttfb == nil || ttfb.TotalCount != 1 {
+		t.Fatalf("TTFBHistogramFromOperations: TotalCount = %v, want 1 (only one op has FirstByte)", ttfb)
+	}
+	if got := ttfb.ValueAtPercentile(100); got < 9*time.Millisecond || got > 11*time.Millisecond {
+		t.Errorf("TTFB p100 = %v, want ~10ms", got)
+	}
+}
+
+func TestHistogramRecordAndPercentile(t *testing.T) {
+	h := NewHistogram(time.Microsecond, 3)
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	if h.TotalCount != 100 {
+		t.Fatalf("TotalCount = %d, want 100", h.TotalCount)
+	}
+	if p50 := h.ValueAtPercentile(50); p50 < 45*time.Millisecond || p50 > 55*time.Millisecond {
+		t.Errorf("p50 = %v, want ~50ms", p50)
+	}
+	if p99 := h.ValueAtPercentile(99); p99 < 95*time.Millisecond || p99 > 100*time.Millisecond {
+		t.Errorf("p99 = %v, want ~95-100ms", p99)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := NewHistogram(time.Microsecond, 3)
+	b := NewHistogram(time.Microsecond, 3)
+	for i := 1; i <= 50; i++ {
+		a.Record(time.Duration(i) * time.Millisecond)
+	}
+	for i := 51; i <= 100; i++ {
+		b.Record(time.Duration(i) * time.Millisecond)
+	}
+	a.Merge(b)
+
+	if a.TotalCount != 100 {
+		t.Fatalf("TotalCount after merge = %d, want 100", a.TotalCount)
+	}
+	if p99 := a.ValueAtPercentile(99); p99 < 95*time.Millisecond || p99 > 100*time.Millisecond {
+		t.Errorf("merged p99 = %v, want ~95-100ms", p99)
+	}
+}
+
+// TestHistogramBucketsMonotonic guards the invariant promexport's
+// histogramCollector relies on: scanning Counts in ascending raw index
+// order, every bucket that Record actually populated must have a
+// strictly increasing upper bound, and the count at a given index must
+// never be revisited under a smaller or equal key.
+func TestHistogramBucketsMonotonic(t *testing.T) {
+	h := NewHistogram(time.Microsecond, 3)
+	for i := 1; i <= 5000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	var lastUpper int64 = -1
+	var cum uint32
+	for idx, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		upper := h.BucketUpperNanos(idx)
+		if upper <= lastUpper {
+			t.Fatalf("bucket upper bound not increasing at idx %d: %d <= %d", idx, upper, lastUpper)
+		}
+		lastUpper = upper
+		cum += c
+	}
+	if int64(cum) != h.TotalCount {
+		t.Fatalf("cumulative count over non-empty buckets = %d, want %d", cum, h.TotalCount)
+	}
+}
@@ -0,0 +1,169 @@
+package aggregate
+
+// NetworkMatrix is an NxN measurement of raw TCP throughput and RTT
+// between every pair of warp client nodes, so a slow or flaky link can
+// be spotted before a distributed S3 benchmark blames S3. This package
+// only builds it from measured edges (NewNetworkMatrix); no warp
+// subcommand runs the measurements, and no report type in this package
+// has a field holding it.
+type NetworkMatrix struct {
+	// Nodes lists the warp-client endpoints measured.
+	Nodes []string `json:"nodes"`
+	// Edges contains one entry per measured, unordered pair of nodes.
+	Edges []NetworkEdge `json:"edges"`
+	// SlowestEdge indexes into Edges, or -1 if Edges is empty.
+	SlowestEdge int `json:"slowest_edge"`
+	// FlaggedNodes lists nodes whose median measured bandwidth across
+	// their edges is below 50% of the overall median edge bandwidth,
+	// i.e. a likely bad NIC rather than a slow peer.
+	FlaggedNodes []string `json:"flagged_nodes,omitempty"`
+}
+
+// NetworkEdge is a single pairwise measurement between two nodes,
+// produced by a short bidirectional saturating stream between them.
+type NetworkEdge struct {
+	NodeA string `json:"node_a"`
+	NodeB string `json:"node_b"`
+
+	BPSMin float64 `json:"bps_min"`
+	BPSAvg float64 `json:"bps_avg"`
+	BPSMax float64 `json:"bps_max"`
+
+	RTTP50Millis float64 `json:"rtt_p50_ms"`
+	RTTP99Millis float64 `json:"rtt_p99_ms"`
+}
+
+// NewNetworkMatrix derives SlowestEdge and FlaggedNodes from a set of
+// already-measured edges between nodes. It runs no measurements
+// itself; the caller supplies the edges.
+func NewNetworkMatrix(nodes []string, edges []NetworkEdge) NetworkMatrix {
+	m := NetworkMatrix{
+		Nodes:       nodes,
+		Edges:       edges,
+		SlowestEdge: -1,
+	}
+	if len(edges) == 0 {
+		return m
+	}
+
+	slowest := 0
+	byNode := make(map[string][]float64, len(nodes))
+	allBPS := make([]float64, 0, len(edges))
+	for i, e := range edges {
+		if e.BPSAvg < edges[slowest].BPSAvg {
+			slowest = i
+		}
+		byNode[e.NodeA] = append(byNode[e.NodeA], e.BPSAvg)
+		byNode[e.NodeB] = append(byNode[e.NodeB], e.BPSAvg)
+		allBPS = append(allBPS, e.BPSAvg)
+	}
+	m.SlowestEdge = slowest
+
+	overall := median(allBPS)
+	if overall <= 0 {
+		return m
+	}
+	for _, node := range nodes {
+		if median(byNode[node]) < overall*0.5 {
+			m.FlaggedNodes = append(m.FlaggedNodes, node)
+		}
+	}
+	return m
+}
+
+// median returns the middle value of vs (or the average of the two
+// middle values for an even-length slice), leaving vs unmodified.
+func median(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), vs...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j-1] > sorted[j]; j-- {
+			sorted[j-1], sorted[j] = sorted[j], sorted[j-1]
+		}
+	}
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// ScheduleNetworkPairs returns a round-robin edge-coloring schedule for
+// the complete graph K_n, using the standard circle method: fix node
+// 0, rotate the remaining nodes each round. Every round's pairs are
+// disjoint, so no node is scheduled into more than one concurrent
+// network test at a time, covering all n*(n-1)/2 pairs in n-1 rounds
+// for even n.
+//
+// For odd n a virtual node is added internally, so there are n rounds;
+// any pair involving it is a bye for that round and is omitted from the
+// result, leaving floor(n/2) pairs in every round.
+func ScheduleNetworkPairs(n int) [][][2]int {
+	if n < 2 {
+		return nil
+	}
+	const bye = -1
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	if n%2 != 0 {
+		nodes = append(nodes, bye)
+	}
+	total := len(nodes)
+	half := total / 2
+
+	schedule := make([][][2]int, 0, total-1)
+	for r := 0; r < total-1; r++ {
+		round := make([][2]int, 0, half)
+		for i := 0; i < half; i++ {
+			a, b := nodes[i], nodes[total-1-i]
+			if a != bye && b != bye {
+				round = append(round, [2]int{a, b})
+			}
+		}
+		schedule = append(schedule, round)
+
+		// Rotate everything but the fixed first node one step.
+		last := nodes[total-1]
+		copy(nodes[2:], nodes[1:total-1])
+		nodes[1] = last
+	}
+	return schedule
+}
+
+// AdaptiveConcurrency drives the doubling concurrency loop each edge
+// of a `warp net` pre-flight test runs: starting at 1, it doubles the
+// number of connections saturating the link and calls measure at each
+// step, stopping once either the next doubling improves throughput by
+// less than 5% (the link has plateaued) or maxConcurrency is reached.
+// It returns the concurrency level it stopped at and the bps measured
+// there.
+//
+// measure is expected to open that many connections over the
+// warp-client control channel to the peer node, run a short,
+// fixed-length bidirectional stream, and return the resulting average
+// bytes per second; driving that measurement over the real
+// gRPC/websocket control channel isn't part of this checkout, so only
+// this transport-agnostic decision loop is implemented here.
+func AdaptiveConcurrency(measure func(concurrency int) float64, maxConcurrency int) (concurrency int, bps float64) {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	concurrency = 1
+	bps = measure(concurrency)
+	for concurrency < maxConcurrency {
+		next := concurrency * 2
+		if next > maxConcurrency {
+			next = maxConcurrency
+		}
+		nextBPS := measure(next)
+		if nextBPS <= bps*1.05 {
+			return concurrency, bps
+		}
+		concurrency, bps = next, nextBPS
+	}
+	return concurrency, bps
+}
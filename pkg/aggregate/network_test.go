@@ -0,0 +1,103 @@
+package aggregate
+
+import "testing"
+
+func checkSchedule(t *testing.T, n int, wantRounds int) {
+	t.Helper()
+	schedule := ScheduleNetworkPairs(n)
+	if len(schedule) != wantRounds {
+		t.Fatalf("got %d rounds, want %d", len(schedule), wantRounds)
+	}
+
+	seen := make(map[[2]int]bool)
+	for _, round := range schedule {
+		inRound := make(map[int]bool)
+		for _, pair := range round {
+			if inRound[pair[0]] || inRound[pair[1]] {
+				t.Fatalf("node scheduled twice within round %v", round)
+			}
+			inRound[pair[0]] = true
+			inRound[pair[1]] = true
+
+			a, b := pair[0], pair[1]
+			if a > b {
+				a, b = b, a
+			}
+			key := [2]int{a, b}
+			if seen[key] {
+				t.Fatalf("pair %v scheduled more than once", key)
+			}
+			seen[key] = true
+		}
+	}
+
+	want := n * (n - 1) / 2
+	if len(seen) != want {
+		t.Fatalf("got %d unique pairs, want %d", len(seen), want)
+	}
+}
+
+func TestScheduleNetworkPairsEven(t *testing.T) {
+	checkSchedule(t, 6, 5)
+}
+
+func TestScheduleNetworkPairsOdd(t *testing.T) {
+	checkSchedule(t, 5, 5)
+}
+
+func TestScheduleNetworkPairsTooSmall(t *testing.T) {
+	if s := ScheduleNetworkPairs(1); s != nil {
+		t.Fatalf("ScheduleNetworkPairs(1) = %v, want nil", s)
+	}
+}
+
+// TestAdaptiveConcurrencyStopsAtPlateau simulates a link whose
+// throughput keeps doubling up to 4 connections, then plateaus
+// (further doubling gains less than 5%), and checks the loop stops
+// right there instead of continuing on to maxConcurrency.
+func TestAdaptiveConcurrencyStopsAtPlateau(t *testing.T) {
+	bpsAt := map[int]float64{
+		1:  100,
+		2:  200,
+		4:  400,
+		8:  410, // < 5% improvement over 4: plateau
+		16: 420,
+	}
+	var calls []int
+	measure := func(concurrency int) float64 {
+		calls = append(calls, concurrency)
+		return bpsAt[concurrency]
+	}
+
+	concurrency, bps := AdaptiveConcurrency(measure, 16)
+	if concurrency != 4 {
+		t.Errorf("concurrency = %d, want 4", concurrency)
+	}
+	if bps != 400 {
+		t.Errorf("bps = %v, want 400", bps)
+	}
+	wantCalls := []int{1, 2, 4, 8}
+	if len(calls) != len(wantCalls) {
+		t.Fatalf("measure called with %v, want %v", calls, wantCalls)
+	}
+	for i, c := range wantCalls {
+		if calls[i] != c {
+			t.Errorf("call %d: concurrency = %d, want %d", i, calls[i], c)
+		}
+	}
+}
+
+// TestAdaptiveConcurrencyRespectsMax checks the loop stops at
+// maxConcurrency even if throughput is still improving.
+func TestAdaptiveConcurrencyRespectsMax(t *testing.T) {
+	measure := func(concurrency int) float64 {
+		return float64(concurrency) * 100
+	}
+	concurrency, bps := AdaptiveConcurrency(measure, 8)
+	if concurrency != 8 {
+		t.Errorf("concurrency = %d, want 8 (capped at maxConcurrency)", concurrency)
+	}
+	if bps != 800 {
+		t.Errorf("bps = %v, want 800", bps)
+	}
+}
@@ -0,0 +1,103 @@
+package aggregate
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+)
+
+// ProfileInfo is a small side-car JSON emitted alongside the aggregate
+// output, recording how expensive the post-processing phase (the
+// sorts in SortByDuration/SortByThroughput, Segment, ...) was for this
+// run, so regressions in analysis cost are observable across releases.
+type ProfileInfo struct {
+	// CPUProfile is the path of the pprof CPU profile, if one was captured.
+	CPUProfile string `json:"cpu_profile,omitempty"`
+	// MemProfile is the path of the pprof heap profile, if one was captured.
+	MemProfile string `json:"mem_profile,omitempty"`
+	// Trace is the path of the runtime/trace output, if one was captured.
+	Trace string `json:"trace,omitempty"`
+	// AnalyzeWallMillis is the wall-clock time spent in the profiled call.
+	AnalyzeWallMillis int64 `json:"analyze_wall_ms"`
+	// AnalyzeAllocBytes is the total bytes allocated during the profiled call.
+	AnalyzeAllocBytes uint64 `json:"analyze_alloc_bytes"`
+}
+
+// ProfileOptions selects which profiles to capture around a call to
+// Analyze. A zero value captures none.
+//
+// Nothing in this checkout populates these from a flag yet: there is
+// no --cpu-profile, --mem-profile, --trace, or --pprof-listen on any
+// warp subcommand, so a caller has to construct ProfileOptions and
+// call Analyze directly until that CLI wiring exists.
+type ProfileOptions struct {
+	CPUProfilePath string
+	MemProfilePath string
+	TracePath      string
+}
+
+// Analyze runs fn - typically SingleOp or another post-processing step
+// over a completed bench.Operations - with the requested profiles
+// enabled around it, and returns a ProfileInfo side-car describing the
+// cost of the call.
+//
+// This wraps the analysis phase specifically, as opposed to the
+// benchmark execution phase: analysis is increasingly expensive on its
+// own for multi-hour runs with millions of operations, and previously
+// had no way to be profiled without patching the binary.
+func Analyze(opts ProfileOptions, fn func()) (ProfileInfo, error) {
+	var info ProfileInfo
+
+	if opts.CPUProfilePath != "" {
+		f, err := os.Create(opts.CPUProfilePath)
+		if err != nil {
+			return info, err
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return info, err
+		}
+		defer pprof.StopCPUProfile()
+		info.CPUProfile = opts.CPUProfilePath
+	}
+
+	if opts.TracePath != "" {
+		f, err := os.Create(opts.TracePath)
+		if err != nil {
+			return info, err
+		}
+		defer f.Close()
+		if err := trace.Start(f); err != nil {
+			return info, err
+		}
+		defer trace.Stop()
+		info.Trace = opts.TracePath
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	fn()
+
+	info.AnalyzeWallMillis = time.Since(start).Milliseconds()
+	runtime.ReadMemStats(&after)
+	info.AnalyzeAllocBytes = after.TotalAlloc - before.TotalAlloc
+
+	if opts.MemProfilePath != "" {
+		f, err := os.Create(opts.MemProfilePath)
+		if err != nil {
+			return info, err
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return info, err
+		}
+		info.MemProfile = opts.MemProfilePath
+	}
+
+	return info, nil
+}
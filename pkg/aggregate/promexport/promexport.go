@@ -0,0 +1,176 @@
+// Package promexport exports warp benchmark results to Prometheus, so
+// results can be scraped into an existing Prometheus deployment
+// instead of parsed out of the aggregate JSON after the fact.
+//
+// This package is reachable from Go code (attach an Exporter to a
+// StreamingAggregator, or call UpdateFromOperation once a run
+// completes, then call ServeBlocking), but no warp subcommand wires it
+// up yet: there is no --prometheus-listen flag or other CLI surface
+// that constructs an Exporter and calls ServeBlocking for a user
+// running warp client/warp get/etc.
+package promexport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/minio/warp/pkg/aggregate"
+)
+
+// Exporter registers warp benchmark metrics on a Prometheus registry
+// and keeps them updated as results arrive, whether from a final
+// aggregate.Operation slice or from the live interval aggregator, so
+// both publish to the same registry.
+type Exporter struct {
+	reg *prometheus.Registry
+
+	throughputBPS *prometheus.GaugeVec
+	throughputOPS *prometheus.GaugeVec
+	errorsTotal   *prometheus.CounterVec
+	durSeconds    *histogramCollector
+	ttfbSeconds   *histogramCollector
+}
+
+// New returns an Exporter with its metrics registered on reg.
+func New(reg *prometheus.Registry) *Exporter {
+	e := &Exporter{
+		reg: reg,
+		throughputBPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "warp_bench_throughput_bps",
+			Help: "Average bytes per second for the operation type.",
+		}, []string{"type", "host"}),
+		throughputOPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "warp_bench_throughput_ops",
+			Help: "Average operations per second for the operation type.",
+		}, []string{"type", "host"}),
+		errorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "warp_bench_errors_total",
+			Help: "Total errors recorded for the operation type.",
+		}, []string{"type", "host"}),
+		durSeconds: newHistogramCollector(
+			"warp_bench_request_duration_seconds",
+			"Request duration for the operation type."),
+		ttfbSeconds: newHistogramCollector(
+			"warp_bench_ttfb_seconds",
+			"Time to first byte for the operation type, where applicable."),
+	}
+	reg.MustRegister(e.throughputBPS, e.throughputOPS, e.errorsTotal, e.durSeconds, e.ttfbSeconds)
+	return e
+}
+
+// UpdateFromOperation updates every metric for op's per-host
+// throughput and, if present, its latency/TTFB histograms. Call once
+// per Operation after a run completes.
+func (e *Exporter) UpdateFromOperation(op aggregate.Operation) {
+	for host, t := range op.ThroughputByHost {
+		e.throughputBPS.WithLabelValues(op.Type, host).Set(t.AverageBPS)
+		e.throughputOPS.WithLabelValues(op.Type, host).Set(t.AverageOPS)
+		e.errorsTotal.WithLabelValues(op.Type, host).Add(float64(t.Errors))
+	}
+	e.durSeconds.set(op.Type, "", op.LatencyHistogram)
+	e.ttfbSeconds.set(op.Type, "", op.TTFBHistogram)
+}
+
+// Send implements aggregate.IntervalSink so an Exporter can be
+// attached directly to a StreamingAggregator for live progress, using
+// the same gauges a completed run updates via UpdateFromOperation.
+func (e *Exporter) Send(st aggregate.IntervalStats) error {
+	e.throughputBPS.WithLabelValues(st.Type, "").Set(st.BPS)
+	if secs := st.IntervalEnd.Sub(st.IntervalStart).Seconds(); secs > 0 {
+		e.throughputOPS.WithLabelValues(st.Type, "").Set(float64(st.Ops) / secs)
+	}
+	e.errorsTotal.WithLabelValues(st.Type, "").Add(float64(st.Errors))
+	return nil
+}
+
+// Close implements aggregate.IntervalSink. There is nothing to flush:
+// the registry keeps serving the last values until the process exits
+// or ServeBlocking's linger period elapses.
+func (e *Exporter) Close() error { return nil }
+
+// ServeBlocking starts an HTTP server exposing reg at /metrics on addr,
+// keeps it up until ctx is done, and then lingers for lingerAfter
+// before shutting down, so Prometheus has time to scrape the final
+// values after the benchmark ends.
+func ServeBlocking(ctx context.Context, addr string, reg *prometheus.Registry, lingerAfter time.Duration) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	time.Sleep(lingerAfter)
+	return srv.Close()
+}
+
+// histogramCollector adapts aggregate.Histogram's log-linear buckets
+// to Prometheus's cumulative-bucket histogram model, so an
+// already-recorded Histogram can be exported directly instead of
+// re-observing every sample through a prometheus.HistogramVec.
+type histogramCollector struct {
+	desc *prometheus.Desc
+
+	mu   sync.RWMutex
+	hist map[[2]string]*aggregate.Histogram
+}
+
+func newHistogramCollector(name, help string) *histogramCollector {
+	return &histogramCollector{
+		desc: prometheus.NewDesc(name, help, []string{"type", "host"}, nil),
+		hist: make(map[[2]string]*aggregate.Histogram),
+	}
+}
+
+func (c *histogramCollector) set(typ, host string, h *aggregate.Histogram) {
+	if h == nil {
+		return
+	}
+	c.mu.Lock()
+	c.hist[[2]string{typ, host}] = h
+	c.mu.Unlock()
+}
+
+func (c *histogramCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *histogramCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key, h := range c.hist {
+		buckets := make(map[float64]uint64, len(h.Counts))
+		var cum uint64
+		for idx, count := range h.Counts {
+			// The log-linear encoding's magnitude bands overlap: raw
+			// indices that Record never produces (every magnitude >= 1
+			// band has a "dead" low half already covered by a smaller
+			// magnitude) decode to an upper bound that aliases an
+			// earlier, already-populated bucket. Those slots are always
+			// zero, so skipping them keeps ascending idx order equal to
+			// ascending upper-bound order and cum strictly non-decreasing
+			// per key, instead of a later dead slot clobbering a small
+			// bucket's correct cumulative count with an inflated one.
+			if count == 0 {
+				continue
+			}
+			cum += uint64(count)
+			buckets[float64(h.BucketUpperNanos(idx))/1e9] = cum
+		}
+		m, err := prometheus.NewConstHistogram(c.desc, uint64(h.TotalCount), float64(h.SumNanos)/1e9, buckets, key[0], key[1])
+		if err != nil {
+			continue
+		}
+		ch <- m
+	}
+}
@@ -0,0 +1,52 @@
+package promexport
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/minio/warp/pkg/aggregate"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestHistogramCollectorCumulativeCountsMonotonic guards against the
+// bucket-aliasing bug where a dead sub-bucket slot in a higher
+// magnitude band decoded to the same upper bound as an earlier,
+// already-populated bucket and clobbered its cumulative count.
+func TestHistogramCollectorCumulativeCountsMonotonic(t *testing.T) {
+	h := aggregate.NewHistogram(time.Microsecond, 3)
+	for i := 1; i <= 5000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	c := newHistogramCollector("test_duration_seconds", "test")
+	c.set("GET", "host1", h)
+
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	close(ch)
+
+	m, ok := <-ch
+	if !ok {
+		t.Fatal("Collect produced no metric")
+	}
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	var lastCum uint64
+	for _, b := range pb.GetHistogram().GetBucket() {
+		cum := b.GetCumulativeCount()
+		if cum < lastCum {
+			t.Fatalf("cumulative count decreased at upper bound %v: %d < %d", b.GetUpperBound(), cum, lastCum)
+		}
+		lastCum = cum
+	}
+	if lastCum != uint64(h.TotalCount) {
+		t.Errorf("final cumulative count = %d, want %d", lastCum, h.TotalCount)
+	}
+}
@@ -0,0 +1,117 @@
+package aggregate
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// stddevDuration returns the sample standard deviation (divisor n-1)
+// of the durations of ops.
+func stddevDuration(ops bench.Operations) time.Duration {
+	n := len(ops)
+	if n < 2 {
+		return 0
+	}
+	var sum float64
+	for _, op := range ops {
+		sum += float64(op.Duration())
+	}
+	mean := sum / float64(n)
+
+	var sqDiffSum float64
+	for _, op := range ops {
+		d := float64(op.Duration()) - mean
+		sqDiffSum += d * d
+	}
+	return time.Duration(math.Sqrt(sqDiffSum / float64(n-1)))
+}
+
+// madDuration returns the median absolute deviation of the durations
+// in ops: median(|x_i - median(x)|).
+//
+// Precondition: ops must already be sorted by duration (its caller,
+// SingleSizedRequests.fill, calls SortByDuration first). madDuration
+// relies on this to take the median of ops itself as just the middle
+// element(s) via medianOfSortedDurations, instead of sorting ops again
+// on every call; passing an unsorted slice silently returns a wrong
+// result rather than panicking.
+func madDuration(ops bench.Operations) time.Duration {
+	n := len(ops)
+	if n == 0 {
+		return 0
+	}
+	med := medianOfSortedDurations(ops)
+	devs := make([]float64, n)
+	for i, op := range ops {
+		devs[i] = math.Abs(float64(op.Duration() - med))
+	}
+	sort.Float64s(devs)
+	return time.Duration(medianOfSortedFloats(devs))
+}
+
+func medianOfSortedDurations(ops bench.Operations) time.Duration {
+	n := len(ops)
+	mid := n / 2
+	if n%2 == 0 {
+		return (ops[mid-1].Duration() + ops[mid].Duration()) / 2
+	}
+	return ops[mid].Duration()
+}
+
+func medianOfSortedFloats(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	mid := n / 2
+	if n%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// stddevBPS returns the sample standard deviation (divisor n-1) of the
+// per-operation bytes-per-second of ops.
+func stddevBPS(ops bench.Operations) float64 {
+	n := len(ops)
+	if n < 2 {
+		return 0
+	}
+	var sum float64
+	for _, op := range ops {
+		sum += op.BytesPerSec().Float()
+	}
+	mean := sum / float64(n)
+
+	var sqDiffSum float64
+	for _, op := range ops {
+		d := op.BytesPerSec().Float() - mean
+		sqDiffSum += d * d
+	}
+	return math.Sqrt(sqDiffSum / float64(n-1))
+}
+
+// madBPS returns the median absolute deviation of the per-operation
+// bytes-per-second of ops: median(|x_i - median(x)|).
+func madBPS(ops bench.Operations) float64 {
+	n := len(ops)
+	if n == 0 {
+		return 0
+	}
+	vals := make([]float64, n)
+	for i, op := range ops {
+		vals[i] = op.BytesPerSec().Float()
+	}
+	sort.Float64s(vals)
+	med := medianOfSortedFloats(vals)
+
+	devs := make([]float64, n)
+	for i, v := range vals {
+		devs[i] = math.Abs(v - med)
+	}
+	sort.Float64s(devs)
+	return medianOfSortedFloats(devs)
+}
@@ -0,0 +1,263 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// IntervalStats is a single interval's worth of rolling statistics for
+// one operation type, produced by StreamingAggregator roughly every
+// --interval seconds while a benchmark is still running.
+type IntervalStats struct {
+	Type          string    `json:"type"`
+	IntervalStart time.Time `json:"interval_start"`
+	IntervalEnd   time.Time `json:"interval_end"`
+	Ops           int       `json:"ops"`
+	BPS           float64   `json:"bps"`
+	Errors        int       `json:"errors"`
+	DurAvgMillis  int       `json:"dur_avg_millis"`
+	DurP50Millis  int       `json:"dur_p50_millis"`
+	DurP90Millis  int       `json:"dur_p90_millis"`
+	DurP99Millis  int       `json:"dur_p99_millis"`
+	TTFBAvgMillis int       `json:"ttfb_avg_millis,omitempty"`
+}
+
+// IntervalSink receives an IntervalStats record on every tick. Send is
+// called from the StreamingAggregator's own tick loop, so implementations
+// must not block it for long.
+type IntervalSink interface {
+	Send(IntervalStats) error
+	Close() error
+}
+
+// StdoutSink prints a one-line human readable summary per interval.
+type StdoutSink struct {
+	w io.Writer
+}
+
+// NewStdoutSink returns an IntervalSink that writes a pretty summary line to w.
+func NewStdoutSink(w io.Writer) *StdoutSink {
+	return &StdoutSink{w: w}
+}
+
+func (s *StdoutSink) Send(st IntervalStats) error {
+	_, err := fmt.Fprintf(s.w, "%-10s %5d ops, %8.2f MiB/s, avg %4dms, p99 %4dms, errors %d\n",
+		st.Type, st.Ops, st.BPS/(1<<20), st.DurAvgMillis, st.DurP99Millis, st.Errors)
+	return err
+}
+
+func (s *StdoutSink) Close() error { return nil }
+
+// JSONLinesSink writes one JSON object per interval per line, e.g. to a
+// file opened for --interval-file.
+type JSONLinesSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONLinesSink returns an IntervalSink that writes newline-delimited JSON to w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONLinesSink) Send(st IntervalStats) error {
+	return s.enc.Encode(st)
+}
+
+func (s *JSONLinesSink) Close() error { return nil }
+
+// HTTPSink keeps the most recently emitted IntervalStats per operation
+// type and serves them as a JSON array, for dashboards that poll
+// GET /metrics/live rather than tail a JSON-lines file.
+type HTTPSink struct {
+	mu     sync.RWMutex
+	latest map[string]IntervalStats
+}
+
+// NewHTTPSink returns an IntervalSink that also implements http.Handler.
+func NewHTTPSink() *HTTPSink {
+	return &HTTPSink{latest: make(map[string]IntervalStats)}
+}
+
+func (h *HTTPSink) Send(st IntervalStats) error {
+	h.mu.Lock()
+	h.latest[st.Type] = st
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *HTTPSink) Close() error { return nil }
+
+// ServeHTTP serves the latest IntervalStats for every operation type as a JSON array.
+func (h *HTTPSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	out := make([]IntervalStats, 0, len(h.latest))
+	for _, st := range h.latest {
+		out = append(out, st)
+	}
+	h.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// perTypeWindow accumulates samples for one operation type within the
+// current, not-yet-flushed interval. The interval's own start/end are
+// tracked by StreamingAggregator, not here, since they must be shared
+// and wall-clock aligned across every type's window.
+type perTypeWindow struct {
+	bytes     int64
+	ops       int
+	errors    int
+	durSum    time.Duration
+	hist      *Histogram
+	ttfbSum   time.Duration
+	ttfbCount int
+}
+
+func newPerTypeWindow() *perTypeWindow {
+	return &perTypeWindow{hist: NewHistogram(time.Microsecond, 3)}
+}
+
+func (w *perTypeWindow) record(op bench.Operation) {
+	w.ops++
+	w.bytes += op.Size
+	if op.Err != "" {
+		w.errors++
+	}
+	d := op.Duration()
+	w.durSum += d
+	w.hist.Record(d)
+	if op.FirstByte != nil {
+		if ttfb := op.FirstByte.Sub(op.Start); ttfb > 0 {
+			w.ttfbSum += ttfb
+			w.ttfbCount++
+		}
+	}
+}
+
+func (w *perTypeWindow) stats(typ string, start, end time.Time) IntervalStats {
+	st := IntervalStats{
+		Type:          typ,
+		IntervalStart: start,
+		IntervalEnd:   end,
+		Ops:           w.ops,
+		Errors:        w.errors,
+		DurP50Millis:  durToMillis(w.hist.ValueAtPercentile(50)),
+		DurP90Millis:  durToMillis(w.hist.ValueAtPercentile(90)),
+		DurP99Millis:  durToMillis(w.hist.ValueAtPercentile(99)),
+	}
+	if secs := end.Sub(start).Seconds(); secs > 0 {
+		st.BPS = float64(w.bytes) / secs
+	}
+	if w.ops > 0 {
+		st.DurAvgMillis = durToMillis(w.durSum / time.Duration(w.ops))
+	}
+	if w.ttfbCount > 0 {
+		st.TTFBAvgMillis = durToMillis(w.ttfbSum / time.Duration(w.ttfbCount))
+	}
+	return st
+}
+
+// StreamingAggregator consumes completed operations off a channel and
+// emits an IntervalStats record per operation type to every attached
+// IntervalSink roughly every Interval. It complements SingleOp, which
+// only produces a summary once bench.Operations is complete, so long
+// runs give actionable feedback while they are still in progress.
+//
+// Nothing in this package feeds it: the caller owns the channel passed
+// to Run, and no warp subcommand or client mode constructs one.
+type StreamingAggregator struct {
+	Interval time.Duration
+	Sinks    []IntervalSink
+
+	mu            sync.Mutex
+	windows       map[string]*perTypeWindow
+	intervalStart time.Time
+}
+
+// NewStreamingAggregator returns a StreamingAggregator that ticks every interval.
+func NewStreamingAggregator(interval time.Duration, sinks ...IntervalSink) *StreamingAggregator {
+	return &StreamingAggregator{
+		Interval: interval,
+		Sinks:    sinks,
+		windows:  make(map[string]*perTypeWindow),
+	}
+}
+
+// Run consumes ops until the channel is closed, emitting one
+// IntervalStats per type to every sink on each tick, then flushing
+// one final, possibly partial, interval. Interval boundaries are
+// aligned to multiples of Interval counted from Go's zero time
+// (time.Now().Truncate(Interval)),
+// not to when the first sample of a run happens to arrive, so that
+// --interval output from different operation types, and from separate
+// warp-client processes with synchronized clocks, shares identical
+// interval_start/interval_end pairs and can be correlated or merged.
+// It blocks until ops is closed.
+func (s *StreamingAggregator) Run(ops <-chan bench.Operation) {
+	s.intervalStart = time.Now().Truncate(s.Interval)
+	boundary := s.intervalStart.Add(s.Interval)
+	timer := time.NewTimer(time.Until(boundary))
+	defer timer.Stop()
+
+	for {
+		select {
+		case op, ok := <-ops:
+			if !ok {
+				s.flush(time.Now())
+				return
+			}
+			s.record(op)
+		case <-timer.C:
+			s.flush(boundary)
+			boundary = boundary.Add(s.Interval)
+			timer.Reset(time.Until(boundary))
+		}
+	}
+}
+
+func (s *StreamingAggregator) record(op bench.Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.windows[op.OpType]
+	if !ok {
+		w = newPerTypeWindow()
+		s.windows[op.OpType] = w
+	}
+	w.record(op)
+}
+
+// flush emits the current window for every type against the fixed
+// interval [s.intervalStart, end) and resets it, so operations that
+// straddle a tick are never double-counted.
+func (s *StreamingAggregator) flush(end time.Time) {
+	s.mu.Lock()
+	windows := s.windows
+	start := s.intervalStart
+	s.windows = make(map[string]*perTypeWindow)
+	s.intervalStart = end
+	s.mu.Unlock()
+
+	for typ, w := range windows {
+		st := w.stats(typ, start, end)
+		for _, sink := range s.Sinks {
+			_ = sink.Send(st)
+		}
+	}
+}
+
+// Close closes every attached sink, returning the first error encountered.
+func (s *StreamingAggregator) Close() error {
+	var firstErr error
+	for _, sink := range s.Sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,97 @@
+package aggregate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+func TestPerTypeWindowRecordAndStats(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	firstByte := base.Add(5 * time.Millisecond)
+
+	w := newPerTypeWindow()
+	w.record(bench.Operation{OpType: "GET", Start: base, FirstByte: &firstByte, End: base.Add(10 * time.Millisecond), Size: 100})
+	w.record(bench.Operation{OpType: "GET", Start: base, End: base.Add(20 * time.Millisecond), Size: 100, Err: "timeout"})
+
+	st := w.stats("GET", base, base.Add(time.Second))
+	if st.Ops != 2 {
+		t.Errorf("Ops = %d, want 2", st.Ops)
+	}
+	if st.Errors != 1 {
+		t.Errorf("Errors = %d, want 1", st.Errors)
+	}
+	if st.BPS != 200 {
+		t.Errorf("BPS = %v, want 200 (200 bytes / 1s)", st.BPS)
+	}
+	if st.DurAvgMillis != 15 {
+		t.Errorf("DurAvgMillis = %d, want 15", st.DurAvgMillis)
+	}
+	if st.TTFBAvgMillis != 5 {
+		t.Errorf("TTFBAvgMillis = %d, want 5 (only one op recorded a FirstByte)", st.TTFBAvgMillis)
+	}
+}
+
+type capturingSink struct {
+	mu    sync.Mutex
+	stats []IntervalStats
+}
+
+func (c *capturingSink) Send(st IntervalStats) error {
+	c.mu.Lock()
+	c.stats = append(c.stats, st)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *capturingSink) Close() error { return nil }
+
+func (c *capturingSink) snapshot() []IntervalStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]IntervalStats(nil), c.stats...)
+}
+
+// TestStreamingAggregatorRunAlignsAndAvoidsDoubleCounting drives Run
+// for a few ticks with a single recorded operation, and checks that
+// every tick-driven interval (all but the final, possibly partial one)
+// starts on an Interval-aligned wall-clock boundary, and that the one
+// recorded operation is counted in exactly one interval rather than
+// straddling or repeating across a flush.
+func TestStreamingAggregatorRunAlignsAndAvoidsDoubleCounting(t *testing.T) {
+	sink := &capturingSink{}
+	const interval = 80 * time.Millisecond
+	agg := NewStreamingAggregator(interval, sink)
+
+	ops := make(chan bench.Operation)
+	done := make(chan struct{})
+	go func() {
+		agg.Run(ops)
+		close(done)
+	}()
+
+	start := time.Now()
+	ops <- bench.Operation{OpType: "GET", Start: start, End: start.Add(time.Millisecond), Size: 100}
+	time.Sleep(3 * interval)
+	close(ops)
+	<-done
+
+	stats := sink.snapshot()
+	if len(stats) == 0 {
+		t.Fatal("no IntervalStats emitted")
+	}
+
+	var totalOps int
+	for i, st := range stats {
+		totalOps += st.Ops
+		isFinal := i == len(stats)-1
+		if !isFinal && !st.IntervalStart.Equal(st.IntervalStart.Truncate(interval)) {
+			t.Errorf("interval %d start %v is not aligned to a %v boundary", i, st.IntervalStart, interval)
+		}
+	}
+	if totalOps != 1 {
+		t.Errorf("total ops across all emitted intervals = %d, want 1 (must not be double-counted)", totalOps)
+	}
+}